@@ -0,0 +1,178 @@
+package goalbert
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OpenURL returns a QueryAction that opens url in the user's default
+// browser via xdg-open.
+func OpenURL(name, url string) QueryAction {
+	resolveBinary("xdg-open")
+	return NewQueryAction(name, exec.Command("xdg-open", url))
+}
+
+// OpenFile returns a QueryAction that opens path with its default
+// application via xdg-open.
+func OpenFile(name, path string) QueryAction {
+	resolveBinary("xdg-open")
+	return NewQueryAction(name, exec.Command("xdg-open", path))
+}
+
+// Notify returns a QueryAction that shows a desktop notification via
+// notify-send.
+func Notify(name, summary, body string) QueryAction {
+	resolveBinary("notify-send")
+	return NewQueryAction(name, exec.Command("notify-send", summary, body))
+}
+
+// RunShell returns a QueryAction that runs script through sh -c.
+func RunShell(name, script string) QueryAction {
+	resolveBinary("sh")
+	return NewQueryAction(name, exec.Command("sh", "-c", script))
+}
+
+// clipboardTool is one candidate command CopyToClipboard can pipe text
+// into, tried in order until one is found on PATH.
+type clipboardTool struct {
+	bin  string
+	args []string
+}
+
+// clipboardTools covers X11 (xclip, xsel), Wayland (wl-copy), and macOS
+// (pbcopy).
+var clipboardTools = []clipboardTool{
+	{"xclip", []string{"-selection", "clipboard"}},
+	{"xsel", []string{"--clipboard", "--input"}},
+	{"wl-copy", nil},
+	{"pbcopy", nil},
+}
+
+// CopyToClipboard returns a QueryAction that copies text to the clipboard,
+// selecting the first of xclip, xsel, wl-copy, or pbcopy found on PATH.
+func CopyToClipboard(name, text string) QueryAction {
+	tool := clipboardTools[0]
+	for _, t := range clipboardTools {
+		if probeBinary(t.bin) {
+			tool = t
+			break
+		}
+	}
+	// Only record the tool CopyToClipboard actually ends up using (falling
+	// back to the default if none were found) as a dependency: earlier
+	// candidates that lost the fallback race aren't required, and
+	// resolveBinary-ing them here would make CheckActionDependencies report
+	// them as missing even though a working fallback was found.
+	resolveBinary(tool.bin)
+
+	script := fmt.Sprintf("printf %%s %s | %s %s", shellQuote(text), tool.bin, strings.Join(tool.args, " "))
+	return NewQueryAction(name, exec.Command("sh", "-c", script))
+}
+
+// probeBinary reports whether name is on PATH, without recording it as a
+// dependency the way resolveBinary does. CopyToClipboard uses it to pick a
+// fallback without polluting CheckActionDependencies with candidates it
+// didn't end up using.
+func probeBinary(name string) bool {
+	_, err := exec.LookPath(name)
+	return err == nil
+}
+
+// shellQuote wraps s in single quotes for safe use in a sh -c script,
+// escaping any single quotes it contains.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var (
+	depsMu   sync.Mutex
+	depsSeen = map[string]bool{}
+	depsOK   = map[string]bool{}
+)
+
+// RequireBinary declares that your plugin depends on the external binary
+// name, without waiting for an action constructor to look it up. Call it
+// unconditionally near the top of main, before checking ALBERT_OP, so it
+// runs (and CheckActionDependencies has something to verify) during
+// OpInitialize. The action constructors alone can't cover this: they
+// normally run while building QueryItem.Actions inside QueryCallback, which
+// only happens during OpQuery, long after OpInitialize already ran as a
+// separate v2 invocation.
+func RequireBinary(name string) {
+	resolveBinary(name)
+}
+
+// resolveBinary looks up name on PATH, remembering whether it was found so
+// CheckActionDependencies and Dependencies can report on it later without
+// repeating the exec.LookPath call.
+func resolveBinary(name string) bool {
+	depsMu.Lock()
+	defer depsMu.Unlock()
+	if depsSeen[name] {
+		return depsOK[name]
+	}
+	_, err := exec.LookPath(name)
+	depsSeen[name] = true
+	depsOK[name] = err == nil
+	return err == nil
+}
+
+// Dependencies returns the external binaries resolved so far, whether via
+// RequireBinary or goalbert's action constructors (OpenURL,
+// CopyToClipboard, Notify, RunShell, OpenFile), sorted for stable output.
+func Dependencies() []string {
+	depsMu.Lock()
+	defer depsMu.Unlock()
+	out := make([]string, 0, len(depsSeen))
+	for name := range depsSeen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// mergeDependencies appends the entries of extra not already present in
+// existing, preserving existing's order.
+func mergeDependencies(existing, extra []string) []string {
+	seen := make(map[string]bool, len(existing))
+	for _, d := range existing {
+		seen[d] = true
+	}
+	out := append([]string{}, existing...)
+	for _, d := range extra {
+		if !seen[d] {
+			seen[d] = true
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// CheckActionDependencies returns an AlbertError listing any binaries
+// resolved so far (by RequireBinary or the action constructors) and found
+// missing on PATH. RunOp calls this during OpInitialize so Albert surfaces a
+// clear dependency failure instead of a cryptic runtime exec error — but
+// that only works for binaries resolved before OpInitialize runs, so
+// declare them with RequireBinary rather than relying on an action
+// constructor to be called first.
+func CheckActionDependencies() error {
+	depsMu.Lock()
+	var missing []string
+	for name, ok := range depsOK {
+		if !ok {
+			missing = append(missing, name)
+		}
+	}
+	depsMu.Unlock()
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return AlbertError{
+		Err:  fmt.Errorf("missing required binaries: %s", strings.Join(missing, ", ")),
+		Code: 255,
+	}
+}
@@ -0,0 +1,91 @@
+package goalbert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRequireBinaryAndCheckActionDependencies(t *testing.T) {
+	const missing = "goalbert-test-definitely-not-a-real-binary"
+	RequireBinary(missing)
+
+	err := CheckActionDependencies()
+	if err == nil {
+		t.Fatal("expected an error for a missing required binary")
+	}
+	aberr, ok := err.(AlbertError)
+	if !ok {
+		t.Fatalf("expected AlbertError, got %T", err)
+	}
+	if aberr.Code == 0 {
+		t.Fatal("expected a non-zero exit code")
+	}
+
+	var found bool
+	for _, d := range Dependencies() {
+		if d == missing {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Dependencies() should include %q once resolved", missing)
+	}
+}
+
+func TestMergeDependencies(t *testing.T) {
+	got := mergeDependencies([]string{"a", "b"}, []string{"b", "c"})
+	want := []string{"a", "b", "c"}
+	if len(got) != len(want) {
+		t.Fatalf("mergeDependencies = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("mergeDependencies = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestCopyToClipboardOnlyRecordsChosenTool(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "xsel"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("write stub xsel: %v", err)
+	}
+
+	oldPath := os.Getenv("PATH")
+	os.Setenv("PATH", dir)
+	defer os.Setenv("PATH", oldPath)
+
+	// Clear any memoized lookups for these binaries so this test isn't
+	// sensitive to what ran before it.
+	depsMu.Lock()
+	for _, tool := range clipboardTools {
+		delete(depsSeen, tool.bin)
+		delete(depsOK, tool.bin)
+	}
+	depsMu.Unlock()
+
+	CopyToClipboard("copy", "hello")
+
+	depsMu.Lock()
+	defer depsMu.Unlock()
+	for _, tool := range clipboardTools {
+		if tool.bin == "xsel" {
+			if !depsOK["xsel"] {
+				t.Fatal("expected xsel to resolve: it's the only clipboard tool on PATH")
+			}
+			continue
+		}
+		if depsSeen[tool.bin] {
+			t.Fatalf("resolveBinary should not have recorded %q: it lost the fallback race to xsel", tool.bin)
+		}
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	in := "it's a test"
+	want := `'it'\''s a test'`
+	if got := shellQuote(in); got != want {
+		t.Fatalf("shellQuote(%q) = %q, want %q", in, got, want)
+	}
+}
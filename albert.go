@@ -128,8 +128,18 @@ type Plugin interface {
 	RunOp(op AlbertOp) error
 }
 
-// Run the given Plugin with the appropriate operation and exit accordingly
+// Run the given Plugin with the appropriate operation and exit accordingly.
+// If Albert negotiated the v3 stdin/stdout protocol, Run instead serves
+// plugin with RunV3 over StdioTransport until stdin is exhausted.
 func Run(plugin Plugin) {
+	if IsProtocolV3() {
+		if err := RunV3(plugin, StdioTransport()); err != nil {
+			glog.Warningf("error: %+v", err)
+			os.Exit(255)
+		}
+		os.Exit(0)
+	}
+
 	op := AlbertOp(os.Getenv("ALBERT_OP"))
 	err := plugin.RunOp(op)
 	if err != nil {
@@ -152,11 +162,36 @@ type DefaultPlugin struct {
 	Meta          Metadata
 	Output        io.Writer
 	QueryCallback func(query string) (QueryResult, error)
+
+	// Middlewares wraps QueryCallback before it's invoked by RunOp, applied
+	// in order so the first entry is the outermost call. See QueryMiddleware
+	// and the standard middlewares in the middleware_*.go files. RateLimitMiddleware,
+	// CacheMiddleware, and DebounceMiddleware keep their state in memory, so
+	// they're only meaningfully stateful across queries under v3's
+	// long-lived process; under v2's per-op re-exec they're harmless no-ops.
+	Middlewares []QueryMiddleware
+
+	// SetupSessionCallback is invoked on OpSetupSession with the Session
+	// created for the user's typing session.
+	SetupSessionCallback func(s *Session) error
+
+	// TeardownSessionCallback is invoked on OpTeardownSession with the
+	// Session before it's discarded.
+	TeardownSessionCallback func(s *Session) error
+
+	// QueryCallbackV2 is an alternative to QueryCallback that also receives
+	// the session-scoped Session, letting plugins cache expensive lookups
+	// (HTTP tokens, DB handles, fuzzy-index snapshots, ...) across the
+	// user's typing session. When set, it takes precedence over
+	// QueryCallback.
+	QueryCallbackV2 func(query string, s *Session) (QueryResult, error)
 }
 
-// NewPlugin configures a DefaultPlugin
-func NewPlugin(name, version, author, trigger string, qc func(query string) (QueryResult, error)) DefaultPlugin {
-	return DefaultPlugin{
+// NewPlugin configures a DefaultPlugin. It returns a pointer so the result
+// can be passed straight into Run/RunV3 and still support v3's SetOutput
+// redirection; embedding DefaultPlugin by value loses that.
+func NewPlugin(name, version, author, trigger string, qc func(query string) (QueryResult, error)) *DefaultPlugin {
+	return &DefaultPlugin{
 		Meta: Metadata{
 			IID:          defaultProtocolVersion,
 			Name:         name,
@@ -170,9 +205,22 @@ func NewPlugin(name, version, author, trigger string, qc func(query string) (Que
 	}
 }
 
-// Metadata returns a copy of the plugin's metadata
+// Metadata returns a copy of the plugin's metadata, with Dependencies
+// extended by whatever external binaries goalbert's action constructors
+// (OpenURL, CopyToClipboard, Notify, RunShell, OpenFile) have resolved so
+// far.
 func (p DefaultPlugin) Metadata() Metadata {
-	return p.Meta
+	m := p.Meta
+	m.Dependencies = mergeDependencies(m.Dependencies, Dependencies())
+	return m
+}
+
+// SetOutput redirects where RunOp writes its JSON. RunV3 uses this to
+// capture one request's response at a time over the long-lived v3 stream,
+// so plugins that want v3 support must pass *DefaultPlugin (or embed it by
+// pointer) into RunV3/Run.
+func (p *DefaultPlugin) SetOutput(w io.Writer) {
+	p.Output = w
 }
 
 // Query is one place where no sane default could exist. This must be
@@ -181,13 +229,36 @@ func (p DefaultPlugin) Metadata() Metadata {
 // query is a string that is input by the user into Albert and may inlude the
 // trigger as part of the query
 func (p DefaultPlugin) Query(query string) (QueryResult, error) {
-	if p.QueryCallback != nil {
-		return p.QueryCallback(query)
+	return p.chain()(query)
+}
+
+// chain builds a QueryFunc from QueryCallback wrapped by each of
+// p.Middlewares, so plugin authors get one place to compose cross-cutting
+// behavior (rate-limiting, caching, debouncing, logging, ...) instead of
+// reimplementing it in every callback.
+func (p DefaultPlugin) chain() QueryFunc {
+	fn := p.QueryCallback
+	if fn == nil {
+		fn = func(query string) (QueryResult, error) {
+			return QueryResult{}, AlbertError{
+				Err:  fmt.Errorf("no behavior defined for query '%s'", query),
+				Code: 255,
+			}
+		}
 	}
-	return QueryResult{}, AlbertError{
-		Err:  fmt.Errorf("no behavior defined for query '%s'", query),
-		Code: 255,
+	return p.chainFrom(fn)
+}
+
+// chainFrom wraps base with each of p.Middlewares, applied in order so the
+// first entry is the outermost call. It's shared by chain (for
+// QueryCallback) and RunOp's OpQuery case (for QueryCallbackV2) so a
+// plugin's middlewares run regardless of which callback style it uses.
+func (p DefaultPlugin) chainFrom(base QueryFunc) QueryFunc {
+	fn := base
+	for i := len(p.Middlewares) - 1; i >= 0; i-- {
+		fn = p.Middlewares[i](fn)
 	}
+	return fn
 }
 
 // RunOp is to take any of operation and run, returning error if any, and
@@ -204,16 +275,53 @@ func (p DefaultPlugin) RunOp(op AlbertOp) error {
 		_, err := p.Output.Write([]byte(p.Meta.Name))
 		return err
 	case OpInitialize:
-		return nil
+		return CheckActionDependencies()
 	case OpFinalize:
 		return nil
 	case OpSetupSession:
-		return nil
+		s := NewSession()
+		if p.SetupSessionCallback != nil {
+			if err := p.SetupSessionCallback(s); err != nil {
+				return err
+			}
+		}
+		return s.save(sessionFile(p.Meta.Trigger))
 	case OpTeardownSession:
+		s, err := loadSession(sessionFile(p.Meta.Trigger))
+		if err != nil {
+			s = NewSession()
+		}
+		defer s.Teardown()
+		if p.TeardownSessionCallback != nil {
+			if err := p.TeardownSessionCallback(s); err != nil {
+				return err
+			}
+		}
+		if err := os.Remove(sessionFile(p.Meta.Trigger)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 		return nil
 	case OpQuery:
 		query := os.Getenv("ALBERT_QUERY")
-		res, err := p.Query(query)
+
+		var res QueryResult
+		var err error
+		if p.QueryCallbackV2 != nil {
+			path := sessionFile(p.Meta.Trigger)
+			s, loadErr := loadSession(path)
+			if loadErr != nil {
+				s = NewSession()
+			}
+			fn := p.chainFrom(func(query string) (QueryResult, error) {
+				return p.QueryCallbackV2(query, s)
+			})
+			res, err = fn(query)
+			if saveErr := s.save(path); err == nil {
+				err = saveErr
+			}
+		} else {
+			res, err = p.Query(query)
+		}
 		if err != nil {
 			return err
 		}
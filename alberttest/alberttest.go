@@ -0,0 +1,170 @@
+// Package alberttest provides helpers for exercising goalbert plugins in
+// tests without a running Albert: driving RunOp directly, asserting on the
+// QueryItems it returns, and replaying a scripted session sequence.
+package alberttest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/coxley/goalbert"
+)
+
+// outputSetter matches goalbert.DefaultPlugin's SetOutput method, letting
+// RunOp redirect a plugin's output to an in-memory buffer without importing
+// goalbert's unexported transport plumbing.
+type outputSetter interface {
+	SetOutput(io.Writer)
+}
+
+// RunOp drives plugin through one Albert op the way the real v2 protocol
+// would: it sets ALBERT_OP/ALBERT_QUERY (restoring their previous values
+// afterward), redirects output to an in-memory buffer when plugin supports
+// it, invokes RunOp, and decodes whatever was written into a QueryResult.
+func RunOp(t *testing.T, plugin goalbert.Plugin, op goalbert.AlbertOp, query string) (goalbert.QueryResult, error) {
+	t.Helper()
+
+	defer setEnv("ALBERT_OP", string(op))()
+	defer setEnv("ALBERT_QUERY", query)()
+
+	var buf bytes.Buffer
+	if setter, ok := plugin.(outputSetter); ok {
+		setter.SetOutput(&buf)
+	}
+
+	if err := plugin.RunOp(op); err != nil {
+		return goalbert.QueryResult{}, err
+	}
+
+	var res goalbert.QueryResult
+	if buf.Len() == 0 {
+		return res, nil
+	}
+	if err := json.Unmarshal(buf.Bytes(), &res); err != nil {
+		t.Fatalf("alberttest: decode result for op %s: %v", op, err)
+	}
+	return res, nil
+}
+
+// setEnv sets key to value and returns a func that restores whatever key
+// was set to beforehand, unsetting it if it wasn't set at all.
+func setEnv(key, value string) func() {
+	prev, had := os.LookupEnv(key)
+	os.Setenv(key, value)
+	return func() {
+		if had {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// ItemMatcher narrows which goalbert.QueryItem AssertItem should find and
+// verify against. Zero-value fields are ignored.
+type ItemMatcher struct {
+	Name        string
+	Description string
+	Completion  string
+}
+
+// AssertItem fails the test unless result contains a QueryItem matching
+// every non-empty field of m.
+func AssertItem(t *testing.T, result goalbert.QueryResult, m ItemMatcher) {
+	t.Helper()
+
+	for _, item := range result.Items {
+		if m.Name != "" && item.Name != m.Name {
+			continue
+		}
+		if m.Description != "" && item.Description != m.Description {
+			continue
+		}
+		if m.Completion != "" && item.Completion != m.Completion {
+			continue
+		}
+		return
+	}
+	t.Fatalf("alberttest: no item in %d item(s) matched %+v", len(result.Items), m)
+}
+
+// ScriptedSession replays a SETUPSESSION -> QUERY... -> TEARDOWNSESSION
+// sequence against one Plugin, for exercising state built with a
+// goalbert.Session across multiple queries.
+type ScriptedSession struct {
+	T      *testing.T
+	Plugin goalbert.Plugin
+}
+
+// Setup runs OpSetupSession.
+func (s ScriptedSession) Setup() error {
+	s.T.Helper()
+	_, err := RunOp(s.T, s.Plugin, goalbert.OpSetupSession, "")
+	return err
+}
+
+// Query runs OpQuery with query and returns its QueryResult.
+func (s ScriptedSession) Query(query string) (goalbert.QueryResult, error) {
+	s.T.Helper()
+	return RunOp(s.T, s.Plugin, goalbert.OpQuery, query)
+}
+
+// Teardown runs OpTeardownSession.
+func (s ScriptedSession) Teardown() error {
+	s.T.Helper()
+	_, err := RunOp(s.T, s.Plugin, goalbert.OpTeardownSession, "")
+	return err
+}
+
+// Run executes Setup, each of queries in order via Query, then Teardown,
+// failing the test immediately on the first error and returning the
+// QueryResult of each query in order.
+func (s ScriptedSession) Run(queries ...string) []goalbert.QueryResult {
+	s.T.Helper()
+
+	if err := s.Setup(); err != nil {
+		s.T.Fatalf("alberttest: setup session: %v", err)
+	}
+
+	results := make([]goalbert.QueryResult, 0, len(queries))
+	for _, q := range queries {
+		res, err := s.Query(q)
+		if err != nil {
+			s.T.Fatalf("alberttest: query %q: %v", q, err)
+		}
+		results = append(results, res)
+	}
+
+	if err := s.Teardown(); err != nil {
+		s.T.Fatalf("alberttest: teardown session: %v", err)
+	}
+	return results
+}
+
+var (
+	iidPattern    = regexp.MustCompile(`^org\.albert\.extension\.external/v\d+(\.\d+)*$`)
+	semverPattern = regexp.MustCompile(`^\d+\.\d+(\.\d+)?(-[0-9A-Za-z.-]+)?$`)
+)
+
+// ValidateMetadata checks that m has an Albert-shaped IID, a non-empty
+// Trigger, and a semver-ish Version, returning an error describing the
+// first problem found. Wire it into your plugin's own TestMain to catch
+// metadata mistakes before they reach Albert.
+func ValidateMetadata(m goalbert.Metadata) error {
+	if !iidPattern.MatchString(m.IID) {
+		return fmt.Errorf("alberttest: invalid IID %q", m.IID)
+	}
+	if strings.TrimSpace(m.Trigger) == "" {
+		return fmt.Errorf("alberttest: empty trigger")
+	}
+	if !semverPattern.MatchString(m.Version) {
+		return fmt.Errorf("alberttest: version %q is not semver", m.Version)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package alberttest
+
+import (
+	"testing"
+
+	"github.com/coxley/goalbert"
+)
+
+func TestRunOpQuery(t *testing.T) {
+	p := goalbert.NewPlugin("t", "0.1", "tester", "t", func(query string) (goalbert.QueryResult, error) {
+		return goalbert.QueryResult{
+			Items: []goalbert.QueryItem{{ID: "1", Name: "hello " + query}},
+		}, nil
+	})
+
+	res, err := RunOp(t, p, goalbert.OpQuery, "world")
+	if err != nil {
+		t.Fatalf("RunOp: %v", err)
+	}
+	AssertItem(t, res, ItemMatcher{Name: "hello world"})
+}
+
+func TestScriptedSession(t *testing.T) {
+	p := goalbert.NewPlugin("t", "0.1", "tester", "t", nil)
+	p.QueryCallbackV2 = func(query string, s *goalbert.Session) (goalbert.QueryResult, error) {
+		hits, _ := s.Get("hits")
+		n, _ := hits.(float64)
+		n++
+		s.Set("hits", n)
+		return goalbert.QueryResult{Items: []goalbert.QueryItem{{ID: query, Name: query}}}, nil
+	}
+
+	sess := ScriptedSession{T: t, Plugin: p}
+	results := sess.Run("a", "b")
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Items[0].Name != "a" || results[1].Items[0].Name != "b" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+}
+
+func TestValidateMetadata(t *testing.T) {
+	valid := goalbert.Metadata{
+		IID:     "org.albert.extension.external/v2.0",
+		Trigger: "t",
+		Version: "0.1",
+	}
+	if err := ValidateMetadata(valid); err != nil {
+		t.Fatalf("expected valid metadata, got %v", err)
+	}
+
+	cases := []struct {
+		name string
+		m    goalbert.Metadata
+	}{
+		{"bad iid", goalbert.Metadata{IID: "not-an-iid", Trigger: "t", Version: "0.1"}},
+		{"empty trigger", goalbert.Metadata{IID: valid.IID, Trigger: "", Version: "0.1"}},
+		{"non-semver version", goalbert.Metadata{IID: valid.IID, Trigger: "t", Version: "latest"}},
+	}
+	for _, c := range cases {
+		if err := ValidateMetadata(c.m); err == nil {
+			t.Errorf("%s: expected an error, got nil", c.name)
+		}
+	}
+}
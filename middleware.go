@@ -0,0 +1,11 @@
+package goalbert
+
+// QueryFunc is the function signature of QueryCallback. It's the type that
+// QueryMiddleware wraps to build a chain of cross-cutting behavior around a
+// query.
+type QueryFunc func(query string) (QueryResult, error)
+
+// QueryMiddleware wraps a QueryFunc with additional behavior, calling next
+// to continue down the chain. Middlewares may return an AlbertError to
+// control the exit code Run uses when the chain is driven from RunOp.
+type QueryMiddleware func(next QueryFunc) QueryFunc
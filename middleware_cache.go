@@ -0,0 +1,93 @@
+package goalbert
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheMiddleware returns a QueryMiddleware that memoizes the QueryResult
+// for a normalized query string for ttl, evicting the least-recently-used
+// entry once more than size queries are cached. Errors are never cached.
+//
+// The cache lives only as long as the closure does, so this is only
+// meaningfully stateful under v3: under v2, RunOp re-execs the plugin binary
+// per op, so a fresh, empty queryCache is built on every query and every
+// lookup misses. Use it under v2 only if you're fine with it being a no-op
+// there.
+func CacheMiddleware(size int, ttl time.Duration) QueryMiddleware {
+	c := &queryCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+	return func(next QueryFunc) QueryFunc {
+		return func(query string) (QueryResult, error) {
+			key := strings.ToLower(strings.TrimSpace(query))
+			if res, ok := c.get(key); ok {
+				return res, nil
+			}
+			res, err := next(query)
+			if err != nil {
+				return res, err
+			}
+			c.set(key, res)
+			return res, nil
+		}
+	}
+}
+
+type cacheEntry struct {
+	key       string
+	result    QueryResult
+	expiresAt time.Time
+}
+
+type queryCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func (c *queryCache) get(key string) (QueryResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return QueryResult{}, false
+	}
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return QueryResult{}, false
+	}
+	c.order.MoveToFront(el)
+	return entry.result, true
+}
+
+func (c *queryCache) set(key string, res QueryResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = &cacheEntry{key: key, result: res, expiresAt: time.Now().Add(c.ttl)}
+		c.order.MoveToFront(el)
+		return
+	}
+
+	el := c.order.PushFront(&cacheEntry{key: key, result: res, expiresAt: time.Now().Add(c.ttl)})
+	c.entries[key] = el
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		if oldest != nil {
+			c.order.Remove(oldest)
+			delete(c.entries, oldest.Value.(*cacheEntry).key)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package goalbert
+
+import (
+	"sync"
+	"time"
+)
+
+// DebounceMiddleware returns a QueryMiddleware that collapses a burst of
+// rapid keystrokes into at most one downstream call per delay: if a query
+// arrives less than delay after the last query that was passed on to next,
+// it returns an empty QueryResult instead of invoking next again. Suppressed
+// queries don't restart that delay. Both v2 (one process per op) and the v3 Transport's
+// read-loop (protocol_v3.go) dispatch one query at a time rather than
+// concurrently, so there's never an in-flight call to cancel — debouncing
+// here means throttling by elapsed wall-clock time between calls, not
+// superseding a call that's still running.
+//
+// Its state only lives as long as the closure does, so it's only
+// meaningfully stateful under v3: under v2, RunOp re-execs the plugin binary
+// per op, so a fresh debouncer is built on every query and d.hasRun is
+// always false — DebounceMiddleware is a harmless no-op there, never a
+// throttle.
+func DebounceMiddleware(delay time.Duration) QueryMiddleware {
+	d := &debouncer{}
+	return func(next QueryFunc) QueryFunc {
+		return func(query string) (QueryResult, error) {
+			if d.shouldSuppress(delay) {
+				return QueryResult{}, nil
+			}
+			res, err := next(query)
+			d.recordCall()
+			return res, err
+		}
+	}
+}
+
+type debouncer struct {
+	mu     sync.Mutex
+	hasRun bool
+	lastAt time.Time
+}
+
+// shouldSuppress reports whether a call arriving now should be suppressed
+// because one was already made less than delay ago. It doesn't track which
+// query that prior call was for: the suppressed call has nothing meaningful
+// of its own to return, so DebounceMiddleware returns an empty QueryResult
+// rather than reusing a different query's result under this one's name.
+func (d *debouncer) shouldSuppress(delay time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.hasRun && time.Since(d.lastAt) < delay
+}
+
+func (d *debouncer) recordCall() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hasRun = true
+	d.lastAt = time.Now()
+}
@@ -0,0 +1,20 @@
+package goalbert
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// LoggingMiddleware returns a QueryMiddleware that emits a glog.Info line
+// with the query, latency, and number of returned items on every call.
+func LoggingMiddleware() QueryMiddleware {
+	return func(next QueryFunc) QueryFunc {
+		return func(query string) (QueryResult, error) {
+			start := time.Now()
+			res, err := next(query)
+			glog.Infof("query=%q latency=%s items=%d err=%v", query, time.Since(start), len(res.Items), err)
+			return res, err
+		}
+	}
+}
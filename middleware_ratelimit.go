@@ -0,0 +1,77 @@
+package goalbert
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimitMiddleware returns a QueryMiddleware that throttles queries using
+// a token-bucket keyed on the query string, refilling at rate tokens/sec up
+// to burst. It's meant to protect external APIs called from QueryCallback
+// against bursts of keystrokes. Requests over the limit return an
+// AlbertError with Code 0 so Albert doesn't surface churn to the user while
+// they're still typing.
+//
+// The buckets live only as long as the closure does, so this is only
+// meaningfully stateful under v3: under v2, RunOp re-execs the plugin binary
+// per op, so a fresh rateLimiter is built on every query and every query
+// starts at full burst. Use it under v2 only if you're fine with it being a
+// no-op there.
+func RateLimitMiddleware(rate float64, burst int) QueryMiddleware {
+	rl := &rateLimiter{
+		rate:    rate,
+		burst:   burst,
+		buckets: make(map[string]*tokenBucket),
+	}
+	return func(next QueryFunc) QueryFunc {
+		return func(query string) (QueryResult, error) {
+			if !rl.allow(query) {
+				return QueryResult{}, AlbertError{
+					Err:  fmt.Errorf("rate limit exceeded for query %q", query),
+					Code: 0,
+				}
+			}
+			return next(query)
+		}
+	}
+}
+
+type tokenBucket struct {
+	mu       sync.Mutex
+	tokens   float64
+	lastSeen time.Time
+}
+
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   int
+	buckets map[string]*tokenBucket
+}
+
+func (rl *rateLimiter) allow(key string) bool {
+	rl.mu.Lock()
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(rl.burst), lastSeen: time.Now()}
+		rl.buckets[key] = b
+	}
+	rl.mu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.lastSeen).Seconds() * rl.rate
+	if b.tokens > float64(rl.burst) {
+		b.tokens = float64(rl.burst)
+	}
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,165 @@
+package goalbert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	var calls int
+	next := func(query string) (QueryResult, error) {
+		calls++
+		return QueryResult{}, nil
+	}
+	fn := RateLimitMiddleware(0, 2)(next) // no refill, burst of 2
+
+	if _, err := fn("q"); err != nil {
+		t.Fatalf("call 1: %v", err)
+	}
+	if _, err := fn("q"); err != nil {
+		t.Fatalf("call 2: %v", err)
+	}
+	if _, err := fn("q"); err == nil {
+		t.Fatal("expected rate limit error on 3rd call within burst")
+	}
+	if calls != 2 {
+		t.Fatalf("next called %d times, want 2", calls)
+	}
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	var calls int
+	next := func(query string) (QueryResult, error) {
+		calls++
+		return QueryResult{Items: []QueryItem{{ID: query}}}, nil
+	}
+	fn := CacheMiddleware(10, time.Minute)(next)
+
+	res1, err := fn("Hello")
+	if err != nil {
+		t.Fatalf("call 1: %v", err)
+	}
+	res2, err := fn("hello")
+	if err != nil {
+		t.Fatalf("call 2: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("next called %d times, want 1 (expected normalized cache hit)", calls)
+	}
+	if res1.Items[0].ID != res2.Items[0].ID {
+		t.Fatalf("cached result mismatch: %+v vs %+v", res1, res2)
+	}
+}
+
+func TestCacheMiddlewareEviction(t *testing.T) {
+	var calls int
+	next := func(query string) (QueryResult, error) {
+		calls++
+		return QueryResult{Items: []QueryItem{{ID: query}}}, nil
+	}
+	fn := CacheMiddleware(1, time.Minute)(next)
+
+	if _, err := fn("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fn("b"); err != nil {
+		t.Fatal(err)
+	}
+	// Cache size is 1, so "a" should have been evicted by "b".
+	if _, err := fn("a"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 {
+		t.Fatalf("next called %d times, want 3 (no reuse after eviction)", calls)
+	}
+}
+
+func TestDebounceMiddleware(t *testing.T) {
+	var calls int
+	next := func(query string) (QueryResult, error) {
+		calls++
+		return QueryResult{Items: []QueryItem{{ID: query}}}, nil
+	}
+	fn := DebounceMiddleware(50 * time.Millisecond)(next)
+
+	res, err := fn("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Items[0].ID != "a" {
+		t.Fatalf("first call result = %+v, want ID %q", res, "a")
+	}
+
+	res, err = fn("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("next called %d times within debounce window, want 1", calls)
+	}
+	if len(res.Items) != 0 {
+		t.Fatalf("suppressed call result = %+v, want empty QueryResult, not a stale/mismatched one", res)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	res, err = fn("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("next called %d times after debounce window elapsed, want 2", calls)
+	}
+	if res.Items[0].ID != "c" {
+		t.Fatalf("call after debounce window result = %+v, want ID %q", res, "c")
+	}
+}
+
+func TestLoggingMiddlewarePassesThrough(t *testing.T) {
+	want := QueryResult{Items: []QueryItem{{ID: "1"}}}
+	next := func(query string) (QueryResult, error) {
+		return want, nil
+	}
+	fn := LoggingMiddleware()(next)
+
+	got, err := fn("q")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got.Items) != 1 || got.Items[0].ID != "1" {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestChainOrdersMiddlewaresOutermostFirst(t *testing.T) {
+	var order []string
+	record := func(name string) QueryMiddleware {
+		return func(next QueryFunc) QueryFunc {
+			return func(query string) (QueryResult, error) {
+				order = append(order, name)
+				return next(query)
+			}
+		}
+	}
+
+	p := DefaultPlugin{
+		QueryCallback: func(query string) (QueryResult, error) {
+			order = append(order, "callback")
+			return QueryResult{}, nil
+		},
+		Middlewares: []QueryMiddleware{record("first"), record("second")},
+	}
+
+	if _, err := p.Query("q"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"first", "second", "callback"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
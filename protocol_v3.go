@@ -0,0 +1,147 @@
+package goalbert
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/golang/glog"
+)
+
+// protocolV3Handshake is the ALBERT_OP value Albert sends on startup to
+// negotiate the newer stdin/stdout protocol instead of one invocation per
+// op.
+const protocolV3Handshake AlbertOp = "PROTO"
+
+// Transport carries the stream RunV3 reads v3Request messages from and
+// writes v3Response messages to. Tests can drive the read-loop with
+// io.Pipe instead of the real process streams.
+type Transport struct {
+	In  io.Reader
+	Out io.Writer
+}
+
+// StdioTransport is the Transport Albert's v3 protocol uses in production.
+func StdioTransport() Transport {
+	return Transport{In: os.Stdin, Out: os.Stdout}
+}
+
+// v3Request is one JSON message read from Transport.In under protocol v3.
+type v3Request struct {
+	Op    AlbertOp `json:"op"`
+	ID    string   `json:"id"`
+	Query string   `json:"query"`
+
+	// Session identifies which of Albert's (possibly several, interleaved)
+	// typing sessions this request belongs to. dispatchV3 exports it as
+	// ALBERT_SESSION so sessionFile can key the on-disk session state by it,
+	// the same way it's already keyed by trigger: without this, two
+	// concurrent sessions multiplexed over one v3 stream would silently
+	// share (and stomp) a single session file.
+	Session string `json:"session"`
+}
+
+// v3Response is one JSON message written to Transport.Out in reply to the
+// v3Request with the same ID.
+type v3Response struct {
+	ID     string          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// outputSetter is implemented by Plugin types that expose where RunOp
+// writes its JSON (DefaultPlugin does via SetOutput), letting RunV3 capture
+// one request's output at a time instead of interleaving them on a shared
+// stream.
+type outputSetter interface {
+	SetOutput(io.Writer)
+}
+
+// IsProtocolV3 reports whether Albert negotiated the v3 stdin/stdout
+// protocol, which it signals with ALBERT_OP=PROTO instead of a normal op.
+func IsProtocolV3() bool {
+	return AlbertOp(os.Getenv("ALBERT_OP")) == protocolV3Handshake
+}
+
+// RunV3 serves plugin over the v3 protocol: a long-lived read-loop that
+// decodes one v3Request at a time from t.In, dispatches it through the same
+// Plugin interface RunOp uses, and writes the framed v3Response to t.Out.
+// It returns nil once t.In is exhausted.
+//
+// plugin must support redirecting RunOp's output per-request (DefaultPlugin
+// does via SetOutput, as long as it's passed by pointer). Without that,
+// RunOp would write each QueryResult straight to the real stdout instead of
+// into the framed v3Response, corrupting the v3 stream, so RunV3 refuses to
+// run at all rather than silently produce garbled output.
+func RunV3(plugin Plugin, t Transport) error {
+	if _, ok := plugin.(outputSetter); !ok {
+		return fmt.Errorf("goalbert: RunV3 requires plugin to implement SetOutput(io.Writer) (pass *DefaultPlugin, not DefaultPlugin); got %T", plugin)
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(t.In))
+	enc := json.NewEncoder(t.Out)
+
+	for {
+		var req v3Request
+		if err := dec.Decode(&req); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		resp := v3Response{ID: req.ID}
+		result, err := dispatchV3(plugin, req)
+		if err != nil {
+			glog.Warningf("v3 op=%s id=%s error: %+v", req.Op, req.ID, err)
+			resp.Error = err.Error()
+		} else {
+			resp.Result = result
+		}
+
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatchV3 runs one v3Request through plugin.RunOp, capturing whatever it
+// writes to Output (if the Plugin supports redirecting it via outputSetter)
+// as the response's raw result payload.
+func dispatchV3(plugin Plugin, req v3Request) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	if setter, ok := plugin.(outputSetter); ok {
+		setter.SetOutput(&buf)
+	}
+
+	if req.Op == OpQuery {
+		os.Setenv("ALBERT_QUERY", req.Query)
+	}
+	// Set ALBERT_SESSION (even when req.Session is empty) so sessionFile
+	// picks up whatever session token Albert sent on this request rather
+	// than one left over from a previous request multiplexed over the same
+	// stream.
+	os.Setenv("ALBERT_SESSION", req.Session)
+
+	if err := plugin.RunOp(req.Op); err != nil {
+		return nil, err
+	}
+
+	raw := bytes.TrimSpace(buf.Bytes())
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	if json.Valid(raw) {
+		return json.RawMessage(raw), nil
+	}
+	// OpName writes a bare string rather than JSON; quote it so it's still
+	// a valid result payload.
+	quoted, err := json.Marshal(string(raw))
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(quoted), nil
+}
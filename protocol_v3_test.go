@@ -0,0 +1,104 @@
+package goalbert
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestRunV3QueryRoundTrip(t *testing.T) {
+	p := NewPlugin("t", "0.1", "tester", "t", func(query string) (QueryResult, error) {
+		return QueryResult{Items: []QueryItem{{ID: "1", Name: "hello " + query}}}, nil
+	})
+
+	inR, inW := io.Pipe()
+	var out bytes.Buffer
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RunV3(p, Transport{In: inR, Out: &out})
+	}()
+
+	if err := json.NewEncoder(inW).Encode(v3Request{Op: OpQuery, ID: "1", Query: "world"}); err != nil {
+		t.Fatalf("write request: %v", err)
+	}
+	inW.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("RunV3 returned error: %v", err)
+	}
+
+	var resp v3Response
+	if err := json.Unmarshal(out.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.ID != "1" {
+		t.Fatalf("id = %q, want %q", resp.ID, "1")
+	}
+	if resp.Error != "" {
+		t.Fatalf("unexpected error in response: %s", resp.Error)
+	}
+
+	var res QueryResult
+	if err := json.Unmarshal(resp.Result, &res); err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if len(res.Items) != 1 || res.Items[0].Name != "hello world" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestRunV3SetsAlbertSessionPerRequest(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+	defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+	var seen []string
+	p := &DefaultPlugin{
+		Meta: Metadata{Trigger: "t"},
+		QueryCallbackV2: func(query string, s *Session) (QueryResult, error) {
+			seen = append(seen, os.Getenv("ALBERT_SESSION"))
+			return QueryResult{}, nil
+		},
+	}
+
+	inR, inW := io.Pipe()
+	var out bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- RunV3(p, Transport{In: inR, Out: &out})
+	}()
+
+	enc := json.NewEncoder(inW)
+	if err := enc.Encode(v3Request{Op: OpQuery, ID: "1", Query: "a", Session: "sess-1"}); err != nil {
+		t.Fatalf("write request 1: %v", err)
+	}
+	if err := enc.Encode(v3Request{Op: OpQuery, ID: "2", Query: "b", Session: "sess-2"}); err != nil {
+		t.Fatalf("write request 2: %v", err)
+	}
+	inW.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("RunV3 returned error: %v", err)
+	}
+
+	if len(seen) != 2 || seen[0] != "sess-1" || seen[1] != "sess-2" {
+		t.Fatalf("ALBERT_SESSION seen by QueryCallbackV2 = %v, want [sess-1 sess-2]", seen)
+	}
+}
+
+func TestRunV3RequiresOutputSetter(t *testing.T) {
+	p := NewPlugin("t", "0.1", "tester", "t", func(query string) (QueryResult, error) {
+		return QueryResult{}, nil
+	})
+
+	// *p dereferences to the DefaultPlugin value type, whose method set
+	// doesn't include SetOutput (pointer receiver), so it can't satisfy
+	// outputSetter.
+	err := RunV3(*p, Transport{In: new(bytes.Buffer), Out: new(bytes.Buffer)})
+	if err == nil {
+		t.Fatal("expected RunV3 to refuse a plugin without SetOutput support")
+	}
+}
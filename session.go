@@ -0,0 +1,136 @@
+package goalbert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Session is a goroutine-safe key/value store scoped to one Albert session,
+// from SETUPSESSION through TEARDOWNSESSION. Plugins use its Get/Set/Delete
+// to cache expensive lookups (HTTP tokens, DB handles-as-serialized-state,
+// fuzzy-index snapshots) for the duration of a user's typing session instead
+// of recomputing them on every OpQuery, by way of the data saved to and
+// restored from sessionFile across v2's per-op re-exec.
+//
+// Context does NOT span that same persisted lifetime: under v2, RunOp
+// re-execs the plugin binary per op, so loadSession always constructs a
+// fresh Session (and a fresh ctx/cancel pair) from the on-disk data — it is
+// never the same Go value SetupSessionCallback saw, and is never cancelled
+// by a later OpTeardownSession in a different process. Context is only
+// useful for bounding work within a single op invocation (e.g. a
+// SetupSessionCallback that kicks off a goroutine it wants cancelled before
+// that invocation's RunOp returns); don't rely on it for anything that
+// needs to outlive the current process.
+type Session struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu   sync.RWMutex
+	data map[string]interface{}
+}
+
+// NewSession creates an empty Session whose Context is cancelled by
+// Teardown, valid only for the lifetime of the current process (see the
+// Session doc comment).
+func NewSession() *Session {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Session{
+		ctx:    ctx,
+		cancel: cancel,
+		data:   make(map[string]interface{}),
+	}
+}
+
+// Context returns a context.Context scoped to this Session value, cancelled
+// once Teardown is called on it. It does not span the session's persisted,
+// cross-process lifetime — see the Session doc comment.
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// Get returns the value stored under key, if any.
+func (s *Session) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	v, ok := s.data[key]
+	return v, ok
+}
+
+// Set stores value under key.
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+}
+
+// Delete removes key from the session.
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+}
+
+// Teardown cancels this Session value's Context. RunOp calls this while
+// handling OpTeardownSession, but under v2 that Session was just restored
+// fresh from disk by loadSession, not the one a prior SetupSessionCallback
+// ran with — see the Session doc comment.
+func (s *Session) Teardown() {
+	s.cancel()
+}
+
+// sessionFile returns the path Session state is persisted to between v2
+// invocations, since each op re-execs the plugin binary. Sessions are keyed
+// by trigger and the parent process's PID (Albert's, which stays the same
+// across those re-execs) so concurrent plugins sharing a runtime dir don't
+// collide.
+//
+// Under v3, a single long-lived process can multiplex several of Albert's
+// typing sessions over one stream, so PID alone can't disambiguate them;
+// dispatchV3 (protocol_v3.go) exports the v3Request's Session token as
+// ALBERT_SESSION, and when present it's folded into the key instead of (not
+// in addition to) the PID so concurrent v3 sessions get distinct files. v2
+// never sets ALBERT_SESSION, so its PID-keyed behavior is unchanged.
+func sessionFile(trigger string) string {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	key := os.Getenv("ALBERT_SESSION")
+	if key == "" {
+		key = fmt.Sprintf("%d", os.Getppid())
+	}
+	return filepath.Join(dir, fmt.Sprintf("goalbert-session-%s-%s.json", trigger, key))
+}
+
+// save persists the session's data to path so it can be restored by a later
+// invocation of the same plugin binary.
+func (s *Session) save(path string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	b, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
+
+// loadSession restores a Session previously persisted to path, returning a
+// fresh Session if none exists yet.
+func loadSession(path string) (*Session, error) {
+	s := NewSession()
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
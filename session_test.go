@@ -0,0 +1,149 @@
+package goalbert
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSessionGetSetDelete(t *testing.T) {
+	s := NewSession()
+
+	if _, ok := s.Get("k"); ok {
+		t.Fatal("expected missing key before Set")
+	}
+
+	s.Set("k", "v")
+	v, ok := s.Get("k")
+	if !ok || v != "v" {
+		t.Fatalf("Get(k) = %v, %v; want v, true", v, ok)
+	}
+
+	s.Delete("k")
+	if _, ok := s.Get("k"); ok {
+		t.Fatal("expected key to be gone after Delete")
+	}
+}
+
+func TestSessionTeardownCancelsContext(t *testing.T) {
+	s := NewSession()
+	select {
+	case <-s.Context().Done():
+		t.Fatal("context already cancelled before Teardown")
+	default:
+	}
+
+	s.Teardown()
+	select {
+	case <-s.Context().Done():
+	default:
+		t.Fatal("expected context to be cancelled after Teardown")
+	}
+}
+
+func TestSessionSaveLoadRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+	defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+	path := sessionFile("trigger")
+
+	s := NewSession()
+	s.Set("token", "abc123")
+	if err := s.save(path); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	loaded, err := loadSession(path)
+	if err != nil {
+		t.Fatalf("loadSession: %v", err)
+	}
+	v, ok := loaded.Get("token")
+	if !ok || v != "abc123" {
+		t.Fatalf("Get(token) = %v, %v; want abc123, true", v, ok)
+	}
+}
+
+func TestSessionFileKeyedByAlbertSession(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+	defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+	os.Setenv("ALBERT_SESSION", "one")
+	pathOne := sessionFile("t")
+	os.Setenv("ALBERT_SESSION", "two")
+	pathTwo := sessionFile("t")
+	os.Unsetenv("ALBERT_SESSION")
+
+	if pathOne == pathTwo {
+		t.Fatalf("expected distinct session files for distinct ALBERT_SESSION values, got %q for both", pathOne)
+	}
+}
+
+func TestLoadSessionMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	s, err := loadSession(dir + "/does-not-exist.json")
+	if err != nil {
+		t.Fatalf("expected no error for a missing session file, got %v", err)
+	}
+	if _, ok := s.Get("anything"); ok {
+		t.Fatal("expected a fresh, empty session")
+	}
+}
+
+func TestRunOpSessionLifecycleAndQueryCallbackV2(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("XDG_RUNTIME_DIR", dir)
+	defer os.Unsetenv("XDG_RUNTIME_DIR")
+
+	var torndown bool
+	p := &DefaultPlugin{
+		Meta:   Metadata{Trigger: "t"},
+		Output: discardWriter{},
+		SetupSessionCallback: func(s *Session) error {
+			s.Set("hits", float64(0))
+			return nil
+		},
+		TeardownSessionCallback: func(s *Session) error {
+			torndown = true
+			return nil
+		},
+		QueryCallbackV2: func(query string, s *Session) (QueryResult, error) {
+			hits, _ := s.Get("hits")
+			n, _ := hits.(float64)
+			n++
+			s.Set("hits", n)
+			return QueryResult{Items: []QueryItem{{ID: query}}}, nil
+		},
+	}
+
+	if err := p.RunOp(OpSetupSession); err != nil {
+		t.Fatalf("OpSetupSession: %v", err)
+	}
+
+	os.Setenv("ALBERT_QUERY", "a")
+	if err := p.RunOp(OpQuery); err != nil {
+		t.Fatalf("OpQuery 1: %v", err)
+	}
+	os.Setenv("ALBERT_QUERY", "b")
+	if err := p.RunOp(OpQuery); err != nil {
+		t.Fatalf("OpQuery 2: %v", err)
+	}
+	os.Unsetenv("ALBERT_QUERY")
+
+	if err := p.RunOp(OpTeardownSession); err != nil {
+		t.Fatalf("OpTeardownSession: %v", err)
+	}
+	if !torndown {
+		t.Fatal("expected TeardownSessionCallback to run")
+	}
+
+	if _, err := os.Stat(sessionFile("t")); !os.IsNotExist(err) {
+		t.Fatalf("expected session file to be removed after teardown, stat err = %v", err)
+	}
+}
+
+// discardWriter satisfies io.Writer without pulling in io/ioutil's
+// deprecated Discard or requiring a real file.
+type discardWriter struct{}
+
+func (discardWriter) Write(p []byte) (int, error) { return len(p), nil }